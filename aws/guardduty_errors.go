@@ -0,0 +1,26 @@
+package aws
+
+import (
+	"errors"
+
+	smithy "github.com/aws/smithy-go"
+	"github.com/turbot/steampipe-plugin-sdk/v3/plugin"
+)
+
+// isNotFoundErrorV2 is the aws-sdk-go-v2 counterpart of isNotFoundError: it
+// matches against the typed smithy API error code via errors.As instead of
+// awserr string matching, since v2 clients no longer return awserr.Error.
+func isNotFoundErrorV2(notFoundErrors []string) plugin.ErrorPredicate {
+	return func(err error) bool {
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) {
+			return false
+		}
+		for _, code := range notFoundErrors {
+			if apiErr.ErrorCode() == code {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,35 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/turbot/steampipe-plugin-sdk/v3/plugin"
+)
+
+// S3Client returns an aws-sdk-go-v2 S3 client for the given region, built
+// from the connection's own configured credentials/region/assumed role via
+// getClientForQuerySupportedRegion, the same way GuardDutyService builds its
+// client. fetchThreatIntelSetFromS3 uses this instead of a default-identity
+// session so ThreatIntelSet file downloads honor the Steampipe connection's
+// configured AWS identity rather than whatever the host happens to have.
+func S3Client(ctx context.Context, d *plugin.QueryData, region string) (*s3.Client, error) {
+	cacheKey := "s3-" + region
+
+	if cachedData, ok := d.ConnectionManager.Cache.Get(cacheKey); ok {
+		return cachedData.(*s3.Client), nil
+	}
+
+	cfg, err := getClientForQuerySupportedRegion(ctx, d, region, s3.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+
+	svc := s3.NewFromConfig(*cfg)
+	d.ConnectionManager.Cache.Set(cacheKey, svc)
+
+	return svc, nil
+}
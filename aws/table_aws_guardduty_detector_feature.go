@@ -0,0 +1,134 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/guardduty"
+	"github.com/aws/aws-sdk-go-v2/service/guardduty/types"
+	"github.com/turbot/steampipe-plugin-sdk/v3/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v3/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v3/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+type detectorFeatureInfo = struct {
+	types.DetectorFeatureConfigurationResult
+	DetectorID string
+}
+
+func tableAwsGuardDutyDetectorFeature(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_guardduty_detector_feature",
+		Description: "AWS GuardDuty Detector Feature",
+		List: &plugin.ListConfig{
+			ParentHydrate: listGuardDutyDetectors,
+			Hydrate:       listGuardDutyDetectorFeatures,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "detector_id", Require: plugin.Optional},
+			},
+		},
+		GetMatrixItem: BuildRegionList,
+		Columns: awsRegionalColumns([]*plugin.Column{
+			{
+				Name:        "detector_id",
+				Description: "The ID of the detector that the feature belongs to.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("DetectorID"),
+			},
+			{
+				Name:        "feature_name",
+				Description: "The name of the detector feature, for example S3_DATA_EVENTS, EKS_AUDIT_LOGS, EBS_MALWARE_PROTECTION, RDS_LOGIN_EVENTS, LAMBDA_NETWORK_LOGS, or RUNTIME_MONITORING.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Name"),
+			},
+			{
+				Name:        "status",
+				Description: "The status of the feature, either ENABLED or DISABLED.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Status"),
+			},
+			{
+				Name:        "additional_configuration",
+				Description: "Additional sub-feature configuration, for example the status of EKS_ADDON_MANAGEMENT under EKS_AUDIT_LOGS.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("AdditionalConfiguration"),
+			},
+			{
+				Name:        "updated_at",
+				Description: "The timestamp at which the feature was last updated.",
+				Type:        proto.ColumnType_TIMESTAMP,
+				Transform:   transform.FromField("UpdatedAt"),
+			},
+			// Standard columns
+			{
+				Name:        "title",
+				Description: resourceInterfaceDescription("title"),
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Name"),
+			},
+			{
+				Name:        "akas",
+				Description: resourceInterfaceDescription("akas"),
+				Type:        proto.ColumnType_JSON,
+				Hydrate:     getAwsGuardDutyDetectorFeatureAkas,
+				Transform:   transform.FromValue(),
+			},
+		}),
+	}
+}
+
+//// LIST FUNCTION
+
+// listGuardDutyDetectorFeatures hydrates each detector's feature
+// configuration from GetDetector, the same way listGuardDutyThreatIntelSets
+// hydrates ThreatIntelSets from a parent detector.
+func listGuardDutyDetectorFeatures(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	detectorID := h.Item.(detectorInfo).DetectorID
+
+	svc, err := GuardDutyService(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	equalQuals := d.KeyColumnQuals
+	if equalQuals["detector_id"] != nil && equalQuals["detector_id"].GetStringValue() != "" && equalQuals["detector_id"].GetStringValue() != detectorID {
+		return nil, nil
+	}
+
+	op, err := svc.GetDetector(ctx, &guardduty.GetDetectorInput{
+		DetectorId: &detectorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, feature := range op.Features {
+		d.StreamLeafListItem(ctx, detectorFeatureInfo{feature, detectorID})
+
+		// Context may get cancelled due to manual cancellation or if the limit has been reached
+		if d.QueryStatus.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+
+	return nil, nil
+}
+
+//// TRANSFORM FUNCTIONS
+
+func getAwsGuardDutyDetectorFeatureAkas(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	plugin.Logger(ctx).Trace("getAwsGuardDutyDetectorFeatureAkas")
+	data := h.Item.(detectorFeatureInfo)
+	region := d.KeyColumnQualString(matrixKeyRegion)
+
+	getCommonColumnsCached := plugin.HydrateFunc(getCommonColumns).WithCache()
+	c, err := getCommonColumnsCached(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	commonColumnData := c.(*awsCommonColumnData)
+	aka := "arn:" + commonColumnData.Partition + ":guardduty:" + region + ":" + commonColumnData.AccountId + ":detector" + "/" + data.DetectorID + "/feature/" + string(data.Name)
+
+	return []string{aka}, nil
+}
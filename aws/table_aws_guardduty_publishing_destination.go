@@ -0,0 +1,257 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/guardduty"
+	"github.com/aws/aws-sdk-go-v2/service/guardduty/types"
+	"github.com/turbot/steampipe-plugin-sdk/v3/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v3/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v3/plugin/transform"
+)
+
+// defaultPublishingDestinationWaitTimeout bounds how long
+// getGuardDutyPublishingDestination waits for a destination to leave
+// PENDING_VERIFICATION.
+const defaultPublishingDestinationWaitTimeout = 5 * time.Minute
+
+//// TABLE DEFINITION
+
+type publishingDestinationInfo = struct {
+	guardduty.DescribePublishingDestinationOutput
+	DestinationID string
+	DetectorID    string
+}
+
+func tableAwsGuardDutyPublishingDestination(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_guardduty_publishing_destination",
+		Description: "AWS GuardDuty Publishing Destination",
+		Get: &plugin.GetConfig{
+			KeyColumns: plugin.AllColumns([]string{"detector_id", "destination_id"}),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundErrorV2([]string{"InvalidInputException", "BadRequestException"}),
+			},
+			Hydrate: getGuardDutyPublishingDestination,
+		},
+		List: &plugin.ListConfig{
+			ParentHydrate: listGuardDutyDetectors,
+			Hydrate:       listGuardDutyPublishingDestinations,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "detector_id", Require: plugin.Optional},
+			},
+		},
+		GetMatrixItem: BuildRegionList,
+		Columns: awsRegionalColumns([]*plugin.Column{
+			{
+				Name:        "destination_id",
+				Description: "The ID of the publishing destination.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("DestinationID"),
+			},
+			{
+				Name:        "detector_id",
+				Description: "The ID of the detector that the publishing destination belongs to.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("DetectorID"),
+			},
+			{
+				Name:        "destination_type",
+				Description: "The type of resource used for the publishing destination, currently only S3.",
+				Type:        proto.ColumnType_STRING,
+				Hydrate:     getGuardDutyPublishingDestination,
+			},
+			{
+				Name:        "destination_arn",
+				Description: "The ARN of the resource (for example, the S3 bucket) where findings are exported.",
+				Type:        proto.ColumnType_STRING,
+				Hydrate:     getGuardDutyPublishingDestination,
+				Transform:   transform.FromField("DestinationProperties.DestinationArn"),
+			},
+			{
+				Name:        "kms_key_arn",
+				Description: "The ARN of the KMS key used to encrypt the exported findings.",
+				Type:        proto.ColumnType_STRING,
+				Hydrate:     getGuardDutyPublishingDestination,
+				Transform:   transform.FromField("DestinationProperties.KmsKeyArn"),
+			},
+			{
+				Name:        "status",
+				Description: "The status of the publishing destination.",
+				Type:        proto.ColumnType_STRING,
+				Hydrate:     getGuardDutyPublishingDestination,
+			},
+			// Standard columns
+			{
+				Name:        "title",
+				Description: resourceInterfaceDescription("title"),
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("DestinationID"),
+			},
+			{
+				Name:        "akas",
+				Description: resourceInterfaceDescription("akas"),
+				Type:        proto.ColumnType_JSON,
+				Hydrate:     getAwsGuardDutyPublishingDestinationAkas,
+				Transform:   transform.FromValue(),
+			},
+		}),
+	}
+}
+
+//// LIST FUNCTION
+
+func listGuardDutyPublishingDestinations(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	detectorID := h.Item.(detectorInfo).DetectorID
+
+	svc, err := GuardDutyService(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	equalQuals := d.KeyColumnQuals
+
+	// Minimize the API call with the given detector_id
+	if equalQuals["detector_id"] != nil {
+		if equalQuals["detector_id"].GetStringValue() != "" {
+			if equalQuals["detector_id"].GetStringValue() != detectorID {
+				return nil, nil
+			}
+		} else if len(getListValues(equalQuals["detector_id"].GetListValue())) > 0 {
+			if !strings.Contains(fmt.Sprint(getListValues(equalQuals["detector_id"].GetListValue())), detectorID) {
+				return nil, nil
+			}
+		}
+	}
+
+	maxResults := int32(50)
+	input := &guardduty.ListPublishingDestinationsInput{
+		DetectorId: &detectorID,
+		MaxResults: maxResults,
+	}
+
+	paginator := guardduty.NewListPublishingDestinationsPaginator(svc, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range page.Destinations {
+			d.StreamLeafListItem(ctx, publishingDestinationInfo{
+				DestinationID: *result.DestinationId,
+				DetectorID:    detectorID,
+			})
+
+			// Context may get cancelled due to manual cancellation or if the limit has been reached
+			if d.QueryStatus.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+//// HYDRATE FUNCTIONS
+
+func getGuardDutyPublishingDestination(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	logger := plugin.Logger(ctx)
+	logger.Trace("getGuardDutyPublishingDestination")
+
+	svc, err := GuardDutyService(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	var detectorID string
+	if h.Item != nil {
+		detectorID = h.Item.(publishingDestinationInfo).DetectorID
+		id = h.Item.(publishingDestinationInfo).DestinationID
+	} else {
+		detectorID = d.KeyColumnQuals["detector_id"].GetStringValue()
+		id = d.KeyColumnQuals["destination_id"].GetStringValue()
+	}
+
+	params := &guardduty.DescribePublishingDestinationInput{
+		DetectorId:    &detectorID,
+		DestinationId: &id,
+	}
+
+	op, err := svc.DescribePublishingDestination(ctx, params)
+	if err != nil {
+		logger.Debug("getGuardDutyPublishingDestination", "ERROR", err)
+		return nil, err
+	}
+
+	// A destination created just before this query may still read back as
+	// PENDING_VERIFICATION while GuardDuty finishes validating write access
+	// to it; wait for that to resolve so callers see its settled status
+	// instead of a transient one.
+	if op.Status == types.PublishingStatusPendingVerification {
+		op, err = waitForGuardDutyPublishingDestinationStatus(ctx, d, detectorID, id, defaultPublishingDestinationWaitTimeout)
+		if err != nil {
+			logger.Debug("getGuardDutyPublishingDestination", "ERROR", err)
+			return nil, err
+		}
+	}
+
+	return publishingDestinationInfo{*op, id, detectorID}, nil
+}
+
+// waitForGuardDutyPublishingDestinationStatus polls
+// DescribePublishingDestination until the destination moves past
+// PENDING_VERIFICATION, or timeout elapses, returning the last observed
+// result either way.
+func waitForGuardDutyPublishingDestinationStatus(ctx context.Context, d *plugin.QueryData, detectorID, destinationID string, timeout time.Duration) (*guardduty.DescribePublishingDestinationOutput, error) {
+	svc, err := GuardDutyService(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := svc.DescribePublishingDestination(ctx, &guardduty.DescribePublishingDestinationInput{
+			DetectorId:    &detectorID,
+			DestinationId: &destinationID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Status != types.PublishingStatusPendingVerification {
+			return resp, nil
+		}
+
+		if time.Now().After(deadline) {
+			return resp, fmt.Errorf("timed out waiting for publishing destination %s to leave PENDING_VERIFICATION after %s", destinationID, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+//// TRANSFORM FUNCTIONS
+
+func getAwsGuardDutyPublishingDestinationAkas(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	plugin.Logger(ctx).Trace("getAwsGuardDutyPublishingDestinationAkas")
+	data := h.Item.(publishingDestinationInfo)
+	region := d.KeyColumnQualString(matrixKeyRegion)
+
+	getCommonColumnsCached := plugin.HydrateFunc(getCommonColumns).WithCache()
+	c, err := getCommonColumnsCached(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	commonColumnData := c.(*awsCommonColumnData)
+	aka := "arn:" + commonColumnData.Partition + ":guardduty:" + region + ":" + commonColumnData.AccountId + ":detector" + "/" + data.DetectorID + "/publishingDestination/" + data.DestinationID
+
+	return []string{aka}, nil
+}